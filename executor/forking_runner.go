@@ -4,15 +4,19 @@
 package executor
 
 import (
-	"context"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"syscall"
 	"time"
 )
 
+// defaultGracePeriod is used when ForkFunctionRunner.GracePeriod is unset.
+const defaultGracePeriod = 10 * time.Second
+
 // FunctionRunner runs a function
 type FunctionRunner interface {
 	Run(f FunctionRequest) error
@@ -27,6 +31,10 @@ type FunctionRequest struct {
 	InputReader   io.ReadCloser
 	OutputWriter  io.Writer
 	ContentLength *int64
+
+	// HTTPRequest is the inbound HTTP request, required by runners that
+	// need to hijack or upgrade the connection, such as WebSocketFunctionRunner.
+	HTTPRequest *http.Request
 }
 
 // ForkFunctionRunner forks a process for each invocation
@@ -34,6 +42,20 @@ type ForkFunctionRunner struct {
 	ExecTimeout   time.Duration
 	LogPrefix     bool
 	LogBufferSize int
+
+	// GracePeriod is how long to wait after TerminationSignal is sent
+	// before escalating to SIGKILL. Defaults to defaultGracePeriod.
+	GracePeriod time.Duration
+	// TerminationSignal is sent to the process group on deadline expiry
+	// or client disconnect, ahead of a forced SIGKILL. Defaults to SIGTERM.
+	TerminationSignal os.Signal
+
+	// LogSink receives stderr lines from the invoked function. See defaultLogSink.
+	LogSink LogSink
+}
+
+func (f *ForkFunctionRunner) logSink() LogSink {
+	return defaultLogSink(f.LogSink, f.LogPrefix)
 }
 
 // Run run a fork for each invocation
@@ -41,37 +63,77 @@ func (f *ForkFunctionRunner) Run(req FunctionRequest) error {
 	log.Printf("Running: %s", req.Process)
 	start := time.Now()
 
-	var cmd *exec.Cmd
-	var ctx context.Context
-	if f.ExecTimeout > time.Millisecond*0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(context.Background(), f.ExecTimeout)
-		defer cancel()
-	} else {
-		ctx = context.Background()
-	}
-
-	cmd = exec.CommandContext(ctx, req.Process, req.ProcessArgs...)
+	cmd := exec.Command(req.Process, req.ProcessArgs...)
+	// Run the child in its own process group so that a termination signal
+	// reaches any of its own children too, not just the direct child.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
+	stdin := &countingReader{Reader: req.InputReader}
 	if req.InputReader != nil {
 		defer req.InputReader.Close()
-		cmd.Stdin = req.InputReader
+		cmd.Stdin = stdin
 	}
 
+	stdout := &countingWriter{Writer: req.OutputWriter}
+
 	cmd.Env = req.Environment
-	cmd.Stdout = req.OutputWriter
+	cmd.Stdout = stdout
 
 	errPipe, _ := cmd.StderrPipe()
 
-	// Prints stderr to console and is picked up by container logging driver.
-	bindLoggingPipe("stderr", errPipe, os.Stderr, f.LogPrefix, f.LogBufferSize)
-
 	if err := cmd.Start(); err != nil {
 		return err
 	}
 
-	err := cmd.Wait()
+	meta := LogMeta{Function: req.Process, RequestID: requestID(req), PID: cmd.Process.Pid}
+	bindLoggingPipe("stderr", errPipe, f.logSink(), meta, f.LogBufferSize)
+
+	waitCh := make(chan error, 1)
+	go func() { waitCh <- cmd.Wait() }()
+
+	var timeoutCh <-chan time.Time
+	if f.ExecTimeout > time.Millisecond*0 {
+		timer := time.NewTimer(f.ExecTimeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	var clientGone <-chan struct{}
+	if req.HTTPRequest != nil {
+		clientGone = req.HTTPRequest.Context().Done()
+	}
+
+	var err error
+	select {
+	case err = <-waitCh:
+	case <-timeoutCh:
+		log.Printf("%s exceeded exec timeout of %s, sending %s", req.Process, f.ExecTimeout, f.terminationSignal())
+		err = f.terminate(cmd, waitCh)
+	case <-clientGone:
+		log.Printf("client disconnected, sending %s to %s", f.terminationSignal(), req.Process)
+		err = f.terminate(cmd, waitCh)
+	}
+
 	done := time.Since(start)
+
+	exitCode, signal := exitStatus(err)
+	userTime, systemTime, maxRSSKB := resourceUsage(cmd.ProcessState)
+
+	result := FunctionResult{
+		Process:     req.Process,
+		ExitCode:    exitCode,
+		Signal:      signal,
+		Duration:    done,
+		UserTime:    userTime,
+		SystemTime:  systemTime,
+		MaxRSSKB:    maxRSSKB,
+		StdinBytes:  stdin.n,
+		StdoutBytes: stdout.n,
+		Err:         err,
+	}
+	result.writeHeaders(req.OutputWriter)
+	result.logTelemetry()
+
 	if err != nil {
 		return fmt.Errorf("%s exited: after %.2fs, error: %s", req.Process, done.Seconds(), err)
 	}
@@ -80,3 +142,41 @@ func (f *ForkFunctionRunner) Run(req FunctionRequest) error {
 
 	return nil
 }
+
+// terminate sends f.terminationSignal() to the process group, waits up to
+// f.GracePeriod for cmd.Wait (delivered on waitCh) to return, and escalates
+// to SIGKILL if the deadline is exceeded.
+func (f *ForkFunctionRunner) terminate(cmd *exec.Cmd, waitCh <-chan error) error {
+	f.signalGroup(cmd, f.terminationSignal())
+
+	grace := f.GracePeriod
+	if grace <= 0 {
+		grace = defaultGracePeriod
+	}
+
+	select {
+	case err := <-waitCh:
+		return err
+	case <-time.After(grace):
+		log.Printf("%s did not exit within grace period of %s, sending SIGKILL", cmd.Path, grace)
+		f.signalGroup(cmd, syscall.SIGKILL)
+		return <-waitCh
+	}
+}
+
+// signalGroup sends sig to the process group rooted at cmd, falling back
+// to signalling just the process if the group can't be resolved.
+func (f *ForkFunctionRunner) signalGroup(cmd *exec.Cmd, sig syscall.Signal) {
+	if pgid, err := syscall.Getpgid(cmd.Process.Pid); err == nil {
+		syscall.Kill(-pgid, sig)
+		return
+	}
+	cmd.Process.Signal(sig)
+}
+
+func (f *ForkFunctionRunner) terminationSignal() syscall.Signal {
+	if sig, ok := f.TerminationSignal.(syscall.Signal); ok {
+		return sig
+	}
+	return syscall.SIGTERM
+}
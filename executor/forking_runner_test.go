@@ -0,0 +1,112 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess isn't a real test. It's re-executed as a subprocess by
+// the tests below, selected by GO_WANT_HELPER_PROCESS, following the
+// pattern used by the os/exec package's own tests.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "no helper command given")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "graceful":
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGTERM)
+		<-sigCh
+		fmt.Println("graceful-exit")
+
+	case "ignore-sigterm":
+		signal.Ignore(syscall.SIGTERM)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func helperEnv() []string {
+	return append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+}
+
+func TestForkFunctionRunner_GracefulTermination(t *testing.T) {
+	runner := &ForkFunctionRunner{
+		ExecTimeout: 100 * time.Millisecond,
+		GracePeriod: 2 * time.Second,
+	}
+
+	var out bytes.Buffer
+	req := FunctionRequest{
+		Process:      os.Args[0],
+		ProcessArgs:  []string{"-test.run=TestHelperProcess", "--", "graceful"},
+		Environment:  helperEnv(),
+		OutputWriter: &out,
+	}
+
+	start := time.Now()
+	err := runner.Run(req)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("expected clean exit after graceful shutdown, got: %s", err)
+	}
+	if !strings.Contains(out.String(), "graceful-exit") {
+		t.Fatalf("expected helper to print graceful-exit, got: %q", out.String())
+	}
+	if elapsed >= runner.GracePeriod {
+		t.Fatalf("expected process to exit well before grace period, took %s", elapsed)
+	}
+}
+
+func TestForkFunctionRunner_ForcedKillFallback(t *testing.T) {
+	runner := &ForkFunctionRunner{
+		ExecTimeout: 100 * time.Millisecond,
+		GracePeriod: 300 * time.Millisecond,
+	}
+
+	req := FunctionRequest{
+		Process:      os.Args[0],
+		ProcessArgs:  []string{"-test.run=TestHelperProcess", "--", "ignore-sigterm"},
+		Environment:  helperEnv(),
+		OutputWriter: io.Discard,
+	}
+
+	start := time.Now()
+	err := runner.Run(req)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("expected an error from a process forcibly killed after ignoring SIGTERM")
+	}
+	if elapsed < runner.GracePeriod {
+		t.Fatalf("expected termination to wait out the grace period (%s), took %s", runner.GracePeriod, elapsed)
+	}
+	if elapsed > runner.GracePeriod+2*time.Second {
+		t.Fatalf("forced kill took too long to take effect: %s", elapsed)
+	}
+}
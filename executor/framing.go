@@ -0,0 +1,47 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxFrameSize bounds the payload a single frame may declare, so a garbled
+// length prefix (or a misbehaving worker) can't make readFrame attempt an
+// allocation large enough to OOM the whole watchdog process.
+const maxFrameSize = 64 * 1024 * 1024
+
+// writeFrame writes payload prefixed with its 4-byte big-endian length.
+// It is the wire format used between PoolFunctionRunner and its workers.
+func writeFrame(w io.Writer, payload []byte) error {
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(payload)))
+
+	if _, err := w.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// readFrame reads a length-prefixed frame written by writeFrame.
+func readFrame(r io.Reader) ([]byte, error) {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+
+	frameLen := binary.BigEndian.Uint32(lenBuf[:])
+	if frameLen > maxFrameSize {
+		return nil, fmt.Errorf("frame length %d exceeds maximum of %d bytes", frameLen, maxFrameSize)
+	}
+
+	payload := make([]byte, frameLen)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
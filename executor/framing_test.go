@@ -0,0 +1,47 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWriteReadFrameRoundTrip(t *testing.T) {
+	cases := [][]byte{
+		nil,
+		[]byte(""),
+		[]byte("hello world"),
+		bytes.Repeat([]byte("x"), 4096),
+	}
+
+	for _, payload := range cases {
+		var buf bytes.Buffer
+		if err := writeFrame(&buf, payload); err != nil {
+			t.Fatalf("writeFrame: %s", err)
+		}
+
+		got, err := readFrame(&buf)
+		if err != nil {
+			t.Fatalf("readFrame: %s", err)
+		}
+
+		if !bytes.Equal(got, payload) && !(len(got) == 0 && len(payload) == 0) {
+			t.Fatalf("round trip mismatch: got %q, want %q", got, payload)
+		}
+	}
+}
+
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	var buf bytes.Buffer
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], maxFrameSize+1)
+	buf.Write(lenBuf[:])
+
+	_, err := readFrame(&buf)
+	if err == nil {
+		t.Fatal("expected readFrame to reject a length prefix above maxFrameSize")
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// LogMeta carries the per-invocation context stamped onto every log line,
+// so that logs from concurrent invocations can be told apart downstream.
+type LogMeta struct {
+	Function  string
+	RequestID string
+	PID       int
+}
+
+// LogSink receives one line of output at a time from a function's stdout
+// or stderr stream. Implementations decide how (and where) that line is
+// recorded: printed to the console, written as JSON, forwarded to syslog.
+type LogSink interface {
+	Write(stream string, line []byte, meta LogMeta)
+}
+
+// defaultLogSink returns override if set, otherwise a PrefixedStderrSink,
+// matching the original stderr-prefixing behaviour. Shared by every
+// runner's LogSink field so the fallback can't drift between them.
+func defaultLogSink(override LogSink, prefix bool) LogSink {
+	if override != nil {
+		return override
+	}
+	return PrefixedStderrSink{Prefix: prefix}
+}
+
+// PrefixedStderrSink is the original of-watchdog behaviour: each line is
+// written to Output (os.Stderr by default), optionally prefixed with the
+// stream name, for consumption by the container's logging driver.
+type PrefixedStderrSink struct {
+	Output io.Writer
+	Prefix bool
+}
+
+// Write implements LogSink.
+func (s PrefixedStderrSink) Write(stream string, line []byte, meta LogMeta) {
+	output := s.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	if s.Prefix {
+		fmt.Fprintf(output, "%s: %s\n", stream, line)
+	} else {
+		fmt.Fprintf(output, "%s\n", line)
+	}
+}
+
+// jsonLogLine is the on-the-wire shape written by JSONLogSink.
+type jsonLogLine struct {
+	Timestamp string `json:"ts"`
+	Stream    string `json:"stream"`
+	Function  string `json:"function,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	PID       int    `json:"pid,omitempty"`
+	Message   string `json:"msg"`
+}
+
+// JSONLogSink writes one JSON object per line to Output (os.Stderr by
+// default), so log shippers like Fluent Bit or Vector can parse structured
+// fields without a sidecar regex.
+type JSONLogSink struct {
+	Output io.Writer
+}
+
+// Write implements LogSink.
+func (s JSONLogSink) Write(stream string, line []byte, meta LogMeta) {
+	output := s.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	entry := jsonLogLine{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Stream:    stream,
+		Function:  meta.Function,
+		RequestID: meta.RequestID,
+		PID:       meta.PID,
+		Message:   string(line),
+	}
+
+	out, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("unable to marshal log line: %s", err)
+		return
+	}
+	output.Write(append(out, '\n'))
+}
+
+// bindLoggingPipe scans a pipe for lines of text and forwards each one to
+// sink, stamped with meta.
+func bindLoggingPipe(stream string, pipe io.Reader, sink LogSink, meta LogMeta, logBufferSize int) {
+	log.Printf("Started logging: %s from function.", stream)
+
+	scanner := bufio.NewScanner(pipe)
+
+	bufferSize := bufio.MaxScanTokenSize
+	if logBufferSize > 0 {
+		bufferSize = logBufferSize
+	}
+	buf := make([]byte, bufferSize)
+	scanner.Buffer(buf, bufferSize)
+
+	go func() {
+		for scanner.Scan() {
+			sink.Write(stream, scanner.Bytes(), meta)
+		}
+
+		// A pipe closed out from under an in-flight Scan (e.g. a pool
+		// worker killed for a timeout or recycle) is expected teardown,
+		// not a logging failure, so it's not worth logging on every cycle.
+		if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) {
+			log.Printf("error scanning %s: %s", stream, err)
+		}
+	}()
+}
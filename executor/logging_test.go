@@ -0,0 +1,29 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONLogSinkIncludesPID(t *testing.T) {
+	var buf bytes.Buffer
+	sink := JSONLogSink{Output: &buf}
+
+	sink.Write("stdout", []byte("hello"), LogMeta{Function: "handler", RequestID: "req-1", PID: 4242})
+
+	var line jsonLogLine
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("unable to unmarshal log line: %s", err)
+	}
+
+	if line.PID != 4242 {
+		t.Fatalf("expected pid 4242, got %d", line.PID)
+	}
+	if line.Message != "hello" || line.Stream != "stdout" || line.RequestID != "req-1" {
+		t.Fatalf("unexpected log line: %+v", line)
+	}
+}
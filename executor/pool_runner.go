@@ -0,0 +1,324 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// poolControlHeader is sent over a worker's control channel ahead of each
+// request frame, carrying the per-invocation metadata that would otherwise
+// need re-forking a process to change.
+type poolControlHeader struct {
+	ContentLength int64    `json:"content_length"`
+	Environment   []string `json:"env"`
+}
+
+// PoolFunctionRunner maintains a bounded pool of pre-forked, long-lived
+// worker processes and speaks a length-prefixed framing protocol to them
+// over stdin/stdout, avoiding the cost of a fork+exec per invocation.
+// ForkFunctionRunner remains the default; this runner is opt-in via mode=pool.
+type PoolFunctionRunner struct {
+	Process     string
+	ProcessArgs []string
+	Environment []string
+
+	ExecTimeout   time.Duration
+	LogPrefix     bool
+	LogBufferSize int
+
+	// PoolMin workers are pre-forked on Start and never reaped.
+	PoolMin int
+	// PoolMax bounds how many workers may be alive (idle + checked out)
+	// at once. Requests beyond this are rejected rather than queued.
+	PoolMax int
+	// PoolIdleTimeout reaps idle workers above PoolMin once they've been
+	// idle longer than this. Zero disables reaping.
+	PoolIdleTimeout time.Duration
+	// MaxRequestsPerWorker recycles a worker after it has served this many
+	// requests. Zero means unlimited.
+	MaxRequestsPerWorker int
+	// WorkerReadyProbe is the single byte a freshly spawned worker must
+	// write to stdout before it is considered ready to serve requests.
+	WorkerReadyProbe byte
+
+	// LogSink receives stderr lines from worker processes. See defaultLogSink.
+	LogSink LogSink
+
+	mu     sync.Mutex
+	idle   []*poolWorker
+	active int
+}
+
+// poolWorker is a single pre-forked child process and its communication
+// channels: stdin/stdout carry framed request/response bodies, control
+// carries per-request headers over a dedicated pipe (fd 3 in the child).
+type poolWorker struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	control io.WriteCloser
+
+	requests  int
+	idleSince time.Time
+}
+
+func (w *poolWorker) kill() {
+	if w.cmd.Process != nil {
+		w.cmd.Process.Kill()
+	}
+	w.cmd.Wait()
+
+	// Unlike stdin/stdout, the control pipe is created manually in
+	// spawnWorker and isn't closed by cmd.Wait, so it must be closed
+	// explicitly or its write end leaks an fd on every teardown.
+	w.control.Close()
+}
+
+// Start pre-forks PoolMin workers and, if PoolIdleTimeout is set, begins
+// reaping workers above PoolMin that have been idle too long.
+func (f *PoolFunctionRunner) Start() error {
+	for i := 0; i < f.PoolMin; i++ {
+		w, err := f.spawnWorker()
+		if err != nil {
+			return fmt.Errorf("unable to pre-fork worker %d/%d: %s", i+1, f.PoolMin, err)
+		}
+
+		f.mu.Lock()
+		f.active++
+		w.idleSince = time.Now()
+		f.idle = append(f.idle, w)
+		f.mu.Unlock()
+	}
+
+	if f.PoolIdleTimeout > 0 {
+		go f.reapIdle()
+	}
+
+	return nil
+}
+
+// Run checks out a worker, forwards the request over the framing protocol
+// and writes the framed response to req.OutputWriter.
+func (f *PoolFunctionRunner) Run(req FunctionRequest) error {
+	w, err := f.checkout()
+	if err != nil {
+		return err
+	}
+
+	healthy := true
+	defer func() { f.checkin(w, healthy) }()
+
+	var body []byte
+	if req.InputReader != nil {
+		defer req.InputReader.Close()
+		body, err = io.ReadAll(req.InputReader)
+		if err != nil {
+			healthy = false
+			return fmt.Errorf("unable to read request body: %s", err)
+		}
+	}
+
+	header := poolControlHeader{Environment: req.Environment}
+	if req.ContentLength != nil {
+		header.ContentLength = *req.ContentLength
+	}
+
+	controlPayload, err := json.Marshal(header)
+	if err != nil {
+		healthy = false
+		return fmt.Errorf("unable to marshal control header: %s", err)
+	}
+
+	if err := writeFrame(w.control, controlPayload); err != nil {
+		healthy = false
+		return fmt.Errorf("unable to write control frame to worker: %s", err)
+	}
+
+	if err := writeFrame(w.stdin, body); err != nil {
+		healthy = false
+		return fmt.Errorf("unable to write request frame to worker: %s", err)
+	}
+
+	type readResult struct {
+		payload []byte
+		err     error
+	}
+	resultCh := make(chan readResult, 1)
+	go func() {
+		payload, err := readFrame(w.stdout)
+		resultCh <- readResult{payload, err}
+	}()
+
+	var timeoutCh <-chan time.Time
+	if f.ExecTimeout > time.Millisecond*0 {
+		timer := time.NewTimer(f.ExecTimeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	select {
+	case res := <-resultCh:
+		if res.err != nil {
+			healthy = false
+			return fmt.Errorf("%s worker exited unexpectedly: %s", f.Process, res.err)
+		}
+
+		w.requests++
+		if f.MaxRequestsPerWorker > 0 && w.requests >= f.MaxRequestsPerWorker {
+			healthy = false
+		}
+
+		_, err := req.OutputWriter.Write(res.payload)
+		return err
+
+	case <-timeoutCh:
+		healthy = false
+		return fmt.Errorf("%s exceeded exec timeout of %s", f.Process, f.ExecTimeout)
+	}
+}
+
+func (f *PoolFunctionRunner) spawnWorker() (*poolWorker, error) {
+	cmd := exec.Command(f.Process, f.ProcessArgs...)
+	cmd.Env = f.Environment
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stdin pipe: %s", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stdout pipe: %s", err)
+	}
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get stderr pipe: %s", err)
+	}
+
+	controlRead, controlWrite, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create control pipe: %s", err)
+	}
+	// Child inherits the control channel as fd 3.
+	cmd.ExtraFiles = []*os.File{controlRead}
+
+	if err := cmd.Start(); err != nil {
+		controlRead.Close()
+		controlWrite.Close()
+		return nil, err
+	}
+	controlRead.Close()
+
+	// A worker serves many requests over its lifetime, so its log stream
+	// is stamped with the function and PID but no single request ID.
+	meta := LogMeta{Function: f.Process, PID: cmd.Process.Pid}
+	bindLoggingPipe("stderr", stderrPipe, f.logSink(), meta, f.LogBufferSize)
+
+	probe := make([]byte, 1)
+	if _, err := io.ReadFull(stdout, probe); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("worker failed ready probe: %s", err)
+	}
+	if probe[0] != f.WorkerReadyProbe {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("worker sent unexpected ready probe byte: 0x%x", probe[0])
+	}
+
+	log.Printf("Pool worker ready: %s (pid=%d)", f.Process, cmd.Process.Pid)
+
+	return &poolWorker{cmd: cmd, stdin: stdin, stdout: stdout, control: controlWrite}, nil
+}
+
+func (f *PoolFunctionRunner) checkout() (*poolWorker, error) {
+	f.mu.Lock()
+	if n := len(f.idle); n > 0 {
+		w := f.idle[n-1]
+		f.idle = f.idle[:n-1]
+		f.mu.Unlock()
+		return w, nil
+	}
+
+	if f.active >= f.poolMax() {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("worker pool exhausted (pool_max=%d)", f.poolMax())
+	}
+	f.active++
+	f.mu.Unlock()
+
+	w, err := f.spawnWorker()
+	if err != nil {
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (f *PoolFunctionRunner) checkin(w *poolWorker, healthy bool) {
+	if !healthy {
+		w.kill()
+		f.mu.Lock()
+		f.active--
+		f.mu.Unlock()
+		return
+	}
+
+	w.idleSince = time.Now()
+	f.mu.Lock()
+	f.idle = append(f.idle, w)
+	f.mu.Unlock()
+}
+
+func (f *PoolFunctionRunner) reapIdle() {
+	interval := f.PoolIdleTimeout / 2
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		f.mu.Lock()
+		now := time.Now()
+		keep := f.idle[:0]
+		for _, w := range f.idle {
+			if f.active > f.PoolMin && now.Sub(w.idleSince) > f.PoolIdleTimeout {
+				f.active--
+				go w.kill()
+				continue
+			}
+			keep = append(keep, w)
+		}
+		f.idle = keep
+		f.mu.Unlock()
+	}
+}
+
+func (f *PoolFunctionRunner) poolMax() int {
+	if f.PoolMax > 0 {
+		return f.PoolMax
+	}
+	if f.PoolMin > 0 {
+		return f.PoolMin
+	}
+	return 1
+}
+
+func (f *PoolFunctionRunner) logSink() LogSink {
+	return defaultLogSink(f.LogSink, f.LogPrefix)
+}
@@ -0,0 +1,33 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// requestIDHeader is the inbound header callers can set to correlate a
+// function invocation's logs with a request they originated elsewhere.
+const requestIDHeader = "X-Call-Id"
+
+// requestID returns req's caller-supplied X-Call-Id, or generates one.
+func requestID(req FunctionRequest) string {
+	if req.HTTPRequest != nil {
+		if id := req.HTTPRequest.Header.Get(requestIDHeader); id != "" {
+			return id
+		}
+	}
+	return generateRequestID()
+}
+
+func generateRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,138 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// FunctionResult captures the outcome of a single function invocation so
+// that callers can tell an OOM-kill or timeout apart from an application
+// error, rather than parsing a free-form error string.
+type FunctionResult struct {
+	Process  string `json:"process"`
+	ExitCode int    `json:"exit_code"`
+	Signal   string `json:"signal,omitempty"`
+
+	Duration   time.Duration `json:"duration_ns"`
+	UserTime   time.Duration `json:"user_time_ns"`
+	SystemTime time.Duration `json:"system_time_ns"`
+	MaxRSSKB   int64         `json:"max_rss_kb"`
+
+	StdinBytes  int64 `json:"stdin_bytes"`
+	StdoutBytes int64 `json:"stdout_bytes"`
+
+	Err error `json:"-"`
+}
+
+// Status reports a short status string for the invocation, suitable for
+// the X-Function-Status response header.
+func (r FunctionResult) Status() string {
+	switch {
+	case r.Signal != "":
+		return "killed"
+	case r.ExitCode != 0:
+		return "error"
+	default:
+		return "done"
+	}
+}
+
+// logTelemetry emits a JSON telemetry line to stderr for log aggregation.
+func (r FunctionResult) logTelemetry() {
+	out, err := json.Marshal(r)
+	if err != nil {
+		log.Printf("unable to marshal function telemetry: %s", err)
+		return
+	}
+	os.Stderr.Write(append(out, '\n'))
+}
+
+// writeHeaders sets status headers on the outbound HTTP response, if the
+// FunctionRequest's OutputWriter is an http.ResponseWriter. Values are set
+// as HTTP trailers since the body may already have been streamed to the
+// client by the time the exit code is known.
+func (r FunctionResult) writeHeaders(w io.Writer) {
+	rw, ok := w.(http.ResponseWriter)
+	if !ok {
+		return
+	}
+
+	rw.Header().Set(http.TrailerPrefix+"X-Function-Status", r.Status())
+	rw.Header().Set(http.TrailerPrefix+"X-Exit-Code", strconv.Itoa(r.ExitCode))
+	rw.Header().Set(http.TrailerPrefix+"X-Function-Duration", r.Duration.String())
+}
+
+// exitStatus unwraps an *exec.ExitError to extract the process exit code
+// and, if the process was killed by a signal, the signal name.
+func exitStatus(err error) (code int, signal string) {
+	if err == nil {
+		return 0, ""
+	}
+
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return -1, ""
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return exitErr.ExitCode(), ""
+	}
+
+	if status.Signaled() {
+		return 128 + int(status.Signal()), status.Signal().String()
+	}
+
+	return status.ExitStatus(), ""
+}
+
+// countingReader wraps an io.Reader, tracking the number of bytes read.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingWriter wraps an io.Writer, tracking the number of bytes written.
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.Writer.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// resourceUsage extracts CPU time and peak RSS from a finished process's
+// state. Returns zero values if state is nil or usage isn't available.
+func resourceUsage(state *os.ProcessState) (userTime, systemTime time.Duration, maxRSSKB int64) {
+	if state == nil {
+		return 0, 0, 0
+	}
+
+	userTime = state.UserTime()
+	systemTime = state.SystemTime()
+
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		maxRSSKB = rusage.Maxrss
+	}
+
+	return userTime, systemTime, maxRSSKB
+}
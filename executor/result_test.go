@@ -0,0 +1,58 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestExitStatusNilError(t *testing.T) {
+	code, signal := exitStatus(nil)
+	if code != 0 || signal != "" {
+		t.Fatalf("expected (0, \"\") for a nil error, got (%d, %q)", code, signal)
+	}
+}
+
+func TestExitStatusNormalExit(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "exit 3")
+	err := cmd.Run()
+
+	code, signal := exitStatus(err)
+	if code != 3 {
+		t.Fatalf("expected exit code 3, got %d", code)
+	}
+	if signal != "" {
+		t.Fatalf("expected no signal for a normal exit, got %q", signal)
+	}
+}
+
+func TestExitStatusSignaled(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "kill -KILL $$")
+	err := cmd.Run()
+
+	code, signal := exitStatus(err)
+	if signal == "" {
+		t.Fatalf("expected a signal name for a killed process, got none (code=%d)", code)
+	}
+	if code != 128+9 {
+		t.Fatalf("expected code 137 (128+SIGKILL) for a killed process, got %d", code)
+	}
+}
+
+func TestResourceUsage(t *testing.T) {
+	if userTime, systemTime, maxRSSKB := resourceUsage(nil); userTime != 0 || systemTime != 0 || maxRSSKB != 0 {
+		t.Fatalf("expected zero usage for a nil state, got (%s, %s, %d)", userTime, systemTime, maxRSSKB)
+	}
+
+	cmd := exec.Command("sh", "-c", "exit 0")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("unable to run helper command: %s", err)
+	}
+
+	_, _, maxRSSKB := resourceUsage(cmd.ProcessState)
+	if maxRSSKB <= 0 {
+		t.Fatalf("expected a positive peak RSS for a finished process, got %d", maxRSSKB)
+	}
+}
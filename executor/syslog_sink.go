@@ -0,0 +1,140 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSyslogTimeout bounds how long a dial or write to the syslog
+// collector may take before SyslogSink gives up on it, so that a dead or
+// unreachable collector degrades logging rather than stalling invocations.
+const defaultSyslogTimeout = 2 * time.Second
+
+// SyslogSink forwards log lines as RFC 5424 syslog messages over a network
+// connection, for environments that run a syslog/Loki-style aggregator
+// instead of scraping container stdout/stderr.
+type SyslogSink struct {
+	Network  string // "udp" or "tcp"
+	Addr     string
+	AppName  string // defaults to "of-watchdog"
+	Hostname string // defaults to os.Hostname()
+
+	// Timeout bounds both connecting to Addr and writing a single message.
+	// Defaults to defaultSyslogTimeout.
+	Timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink dials addr over network ("udp" or "tcp") and returns a sink
+// ready to forward log lines to it.
+func NewSyslogSink(network, addr string) (*SyslogSink, error) {
+	conn, err := net.DialTimeout(network, addr, defaultSyslogTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial syslog server %s: %s", addr, err)
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &SyslogSink{
+		Network:  network,
+		Addr:     addr,
+		Hostname: hostname,
+		conn:     conn,
+	}, nil
+}
+
+func (s *SyslogSink) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return defaultSyslogTimeout
+}
+
+// Write implements LogSink, formatting and sending one RFC 5424 message
+// per line. Errors are swallowed; a down syslog collector shouldn't take
+// the function invocation down with it.
+func (s *SyslogSink) Write(stream string, line []byte, meta LogMeta) {
+	appName := s.AppName
+	if appName == "" {
+		appName = "of-watchdog"
+	}
+
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	msgID := sanitizeSyslogField(meta.RequestID)
+	if msgID == "" {
+		msgID = "-"
+	}
+
+	procID := "-"
+	if meta.PID > 0 {
+		procID = strconv.Itoa(meta.PID)
+	}
+
+	// facility 1 (user-level messages); severity 3 (err) for stderr, 6 (info) otherwise.
+	severity := 6
+	if stream == "stderr" {
+		severity = 3
+	}
+	priority := 1*8 + severity
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s %s - %s\n",
+		priority,
+		time.Now().UTC().Format(time.RFC3339Nano),
+		hostname,
+		appName,
+		procID,
+		msgID,
+		line,
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout()))
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		log.Printf("syslog write to %s failed, reconnecting: %s", s.Addr, err)
+		s.conn.Close()
+
+		conn, dialErr := net.DialTimeout(s.Network, s.Addr, s.timeout())
+		if dialErr != nil {
+			log.Printf("unable to reconnect to syslog server %s: %s", s.Addr, dialErr)
+			return
+		}
+		s.conn = conn
+		s.conn.SetWriteDeadline(time.Now().Add(s.timeout()))
+		s.conn.Write([]byte(msg))
+	}
+}
+
+// Close closes the underlying network connection.
+func (s *SyslogSink) Close() error {
+	return s.conn.Close()
+}
+
+// sanitizeSyslogField strips characters that would break RFC 5424 field
+// parsing or allow a caller-supplied value (e.g. a request ID) to inject a
+// forged log line, replacing ASCII space, ASCII control characters and DEL
+// with '_'. Non-ASCII runes are passed through unchanged.
+func sanitizeSyslogField(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+)
+
+// TokenMode selects how a stream of bytes is split into discrete frames.
+type TokenMode string
+
+const (
+	// TokenModeLine emits one frame per newline-terminated line (default).
+	TokenModeLine TokenMode = "line"
+	// TokenModeDelimiter emits one frame per occurrence of Delimiter.
+	TokenModeDelimiter TokenMode = "delimiter"
+	// TokenModeInterval emits whatever has been buffered every Interval.
+	TokenModeInterval TokenMode = "interval"
+)
+
+// FrameTokenizer splits a reader into frames according to Mode, calling
+// emit for each frame. It is used by WebSocketFunctionRunner to decide how
+// stdout/stderr bytes are batched into outbound WebSocket frames.
+type FrameTokenizer struct {
+	Mode      TokenMode
+	Delimiter byte
+	Interval  time.Duration
+}
+
+// run reads from r until EOF or error, invoking emit for each frame.
+func (t FrameTokenizer) run(r io.Reader, emit func([]byte)) error {
+	switch t.Mode {
+	case TokenModeDelimiter:
+		return t.runSplit(r, emit)
+	case TokenModeInterval:
+		return t.runInterval(r, emit)
+	default:
+		return t.runSplit(r, emit)
+	}
+}
+
+func (t FrameTokenizer) runSplit(r io.Reader, emit func([]byte)) error {
+	scanner := bufio.NewScanner(r)
+
+	if t.Mode == TokenModeDelimiter {
+		delim := t.Delimiter
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			if atEOF && len(data) == 0 {
+				return 0, nil, nil
+			}
+			if i := bytes.IndexByte(data, delim); i >= 0 {
+				return i + 1, data[:i], nil
+			}
+			if atEOF {
+				return len(data), data, nil
+			}
+			return 0, nil, nil
+		})
+	}
+
+	for scanner.Scan() {
+		emit(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// runInterval accumulates bytes read from r and flushes whatever has been
+// buffered to emit every Interval, rather than waiting for a delimiter.
+func (t FrameTokenizer) runInterval(r io.Reader, emit func([]byte)) error {
+	type chunk struct {
+		data []byte
+		err  error
+	}
+
+	chunks := make(chan chunk)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				b := make([]byte, n)
+				copy(b, buf[:n])
+				chunks <- chunk{data: b}
+			}
+			if err != nil {
+				chunks <- chunk{err: err}
+				return
+			}
+		}
+	}()
+
+	interval := t.Interval
+	if interval <= 0 {
+		interval = 250 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pending bytes.Buffer
+	for {
+		select {
+		case c := <-chunks:
+			if c.err != nil {
+				if pending.Len() > 0 {
+					emit(pending.Bytes())
+				}
+				if c.err == io.EOF {
+					return nil
+				}
+				return c.err
+			}
+			pending.Write(c.data)
+		case <-ticker.C:
+			if pending.Len() > 0 {
+				emit(pending.Bytes())
+				pending.Reset()
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameTokenizerLine(t *testing.T) {
+	r := strings.NewReader("one\ntwo\nthree")
+
+	var got []string
+	err := FrameTokenizer{Mode: TokenModeLine}.run(r, func(b []byte) {
+		got = append(got, string(b))
+	})
+	if err != nil {
+		t.Fatalf("run: %s", err)
+	}
+
+	want := []string{"one", "two", "three"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFrameTokenizerDelimiter(t *testing.T) {
+	r := strings.NewReader("a;b;c;")
+
+	var got []string
+	err := FrameTokenizer{Mode: TokenModeDelimiter, Delimiter: ';'}.run(r, func(b []byte) {
+		got = append(got, string(b))
+	})
+	if err != nil {
+		t.Fatalf("run: %s", err)
+	}
+
+	want := []string{"a", "b", "c"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFrameTokenizerInterval(t *testing.T) {
+	pr, pw := io.Pipe()
+
+	var got []string
+	done := make(chan error, 1)
+	go func() {
+		done <- FrameTokenizer{Mode: TokenModeInterval, Interval: 20 * time.Millisecond}.run(pr, func(b []byte) {
+			got = append(got, string(b))
+		})
+	}()
+
+	pw.Write([]byte("hello"))
+	time.Sleep(50 * time.Millisecond)
+	pw.Write([]byte("world"))
+	pw.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("run: %s", err)
+	}
+
+	if len(got) < 2 {
+		t.Fatalf("expected at least 2 interval-flushed frames, got %v", got)
+	}
+	if strings.Join(got, "") != "helloworld" {
+		t.Fatalf("expected frames to concatenate to the full input, got %v", got)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,225 @@
+// Copyright (c) OpenFaaS Author(s) 2021. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// teeReader returns two readers that each observe the full contents of r:
+// driver must be read to completion to pump bytes into mirror, which closes
+// once driver reaches EOF or errors. This lets stderr be both logged via
+// bindLoggingPipe and forwarded over the WebSocket from the same pipe.
+func teeReader(r io.Reader) (driver io.Reader, mirror io.Reader) {
+	pr, pw := io.Pipe()
+	return &closeOnEOFReader{r: io.TeeReader(r, pw), pw: pw}, pr
+}
+
+type closeOnEOFReader struct {
+	r  io.Reader
+	pw *io.PipeWriter
+}
+
+func (c *closeOnEOFReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil {
+		c.pw.CloseWithError(err)
+	}
+	return n, err
+}
+
+// Stream tags identify which process pipe an outbound WebSocket frame
+// carries bytes for. The tag is the first byte of every binary frame.
+const (
+	streamStdout byte = 1
+	streamStderr byte = 2
+)
+
+// wsControlMessage is sent as a final text frame before the socket is
+// closed, so that a client can tell a clean exit from a killed one.
+type wsControlMessage struct {
+	Type       string `json:"type"`
+	ExitCode   int    `json:"exit_code"`
+	Signal     string `json:"signal,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// WebSocketFunctionRunner upgrades each incoming HTTP request to a
+// WebSocket connection and spawns one child process per connection,
+// relaying stdin/stdout/stderr over framed WebSocket messages. This
+// unlocks long-running, interactive functions that don't fit the
+// request/response model of ForkFunctionRunner.
+type WebSocketFunctionRunner struct {
+	ExecTimeout     time.Duration
+	LogPrefix       bool
+	LogBufferSize   int
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// StdoutTokenizer and StderrTokenizer control how bytes read from the
+	// child's stdout/stderr are batched into outbound frames. They default
+	// to line-based tokenization when left unset.
+	StdoutTokenizer FrameTokenizer
+	StderrTokenizer FrameTokenizer
+
+	// LogSink receives stderr lines from the invoked function. See defaultLogSink.
+	LogSink LogSink
+}
+
+func (f *WebSocketFunctionRunner) logSink() LogSink {
+	return defaultLogSink(f.LogSink, f.LogPrefix)
+}
+
+// Run upgrades the request to a WebSocket, forks req.Process and relays
+// data between the socket and the child process until it exits.
+func (f *WebSocketFunctionRunner) Run(req FunctionRequest) error {
+	if req.HTTPRequest == nil {
+		return fmt.Errorf("websocket mode requires an HTTP request")
+	}
+
+	rw, ok := req.OutputWriter.(http.ResponseWriter)
+	if !ok {
+		return fmt.Errorf("websocket mode requires an http.ResponseWriter")
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  f.ReadBufferSize,
+		WriteBufferSize: f.WriteBufferSize,
+		CheckOrigin:     func(r *http.Request) bool { return true },
+	}
+
+	conn, err := upgrader.Upgrade(rw, req.HTTPRequest, nil)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade to websocket: %s", err)
+	}
+	defer conn.Close()
+
+	log.Printf("Running (ws): %s", req.Process)
+	start := time.Now()
+
+	ctx := context.Background()
+	if f.ExecTimeout > time.Millisecond*0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.ExecTimeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, req.Process, req.ProcessArgs...)
+	cmd.Env = req.Environment
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("unable to get stdin pipe for %s: %s", req.Process, err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("unable to get stdout pipe for %s: %s", req.Process, err)
+	}
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("unable to get stderr pipe for %s: %s", req.Process, err)
+	}
+
+	// Tee stderr so it's both forwarded over the socket and handled by the
+	// existing container-side logging machinery.
+	stderrForLog, stderrForSocket := teeReader(stderrPipe)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	meta := LogMeta{Function: req.Process, RequestID: requestID(req), PID: cmd.Process.Pid}
+	bindLoggingPipe("stderr", stderrForLog, f.logSink(), meta, f.LogBufferSize)
+
+	var writeMu sync.Mutex
+	writeFrame := func(stream byte, data []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		frame := make([]byte, len(data)+1)
+		frame[0] = stream
+		copy(frame[1:], data)
+		return conn.WriteMessage(websocket.BinaryMessage, frame)
+	}
+
+	go f.relayInbound(conn, stdin)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		f.stdoutTokenizer().run(stdout, func(b []byte) { writeFrame(streamStdout, b) })
+	}()
+	go func() {
+		defer wg.Done()
+		f.stderrTokenizer().run(stderrForSocket, func(b []byte) { writeFrame(streamStderr, b) })
+	}()
+
+	waitErr := cmd.Wait()
+	wg.Wait()
+
+	done := time.Since(start)
+	exitCode, signal := exitStatus(waitErr)
+
+	control := wsControlMessage{
+		Type:       "exit",
+		ExitCode:   exitCode,
+		Signal:     signal,
+		DurationMS: done.Milliseconds(),
+	}
+	if payload, err := json.Marshal(control); err == nil {
+		writeMu.Lock()
+		conn.WriteMessage(websocket.TextMessage, payload)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		writeMu.Unlock()
+	}
+
+	log.Printf("%s done (ws): %.2fs secs", req.Process, done.Seconds())
+
+	return waitErr
+}
+
+// relayInbound reads frames from the client and writes their payload to
+// the child process's stdin until the connection closes.
+func (f *WebSocketFunctionRunner) relayInbound(conn *websocket.Conn, stdin interface {
+	Write([]byte) (int, error)
+	Close() error
+}) {
+	defer stdin.Close()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if _, err := stdin.Write(data); err != nil {
+			return
+		}
+	}
+}
+
+func (f *WebSocketFunctionRunner) stdoutTokenizer() FrameTokenizer {
+	if f.StdoutTokenizer.Mode == "" {
+		return FrameTokenizer{Mode: TokenModeLine}
+	}
+	return f.StdoutTokenizer
+}
+
+func (f *WebSocketFunctionRunner) stderrTokenizer() FrameTokenizer {
+	if f.StderrTokenizer.Mode == "" {
+		return FrameTokenizer{Mode: TokenModeLine}
+	}
+	return f.StderrTokenizer
+}